@@ -0,0 +1,118 @@
+// Copyright 2017 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"istio.io/istio/security/pkg/pki"
+)
+
+// SignerBundle holds the signer's own cert&key plus the ordered chain of
+// intermediate certificates (starting with the signer cert itself) needed to
+// validate certs it issues up to a trusted root.
+type SignerBundle struct {
+	Cert  *x509.Certificate
+	Priv  crypto.PrivateKey
+	Chain []*x509.Certificate
+}
+
+// LoadSignerCredsWithChainFromFiles loads the signer cert&key like
+// LoadSignerCredsFromFiles, plus an intermediate chain file (a sequence of
+// concatenated PEM certificates, signer-most first). Each certificate in the
+// chain is verified to be signed by the next, and the last by rootCertFile,
+// before the bundle is returned.
+func LoadSignerCredsWithChainFromFiles(signerCertFile, signerPrivFile, chainFile, rootCertFile string) (*SignerBundle, error) {
+	cert, key, err := LoadSignerCredsFromFiles(signerCertFile, signerPrivFile)
+	if err != nil {
+		return nil, err
+	}
+
+	chainBytes, err := ioutil.ReadFile(chainFile)
+	if err != nil {
+		return nil, fmt.Errorf("certificate chain file reading failure (%v)", err)
+	}
+	chain, err := pki.ParsePemEncodedCertificateChain(chainBytes)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 || !chain[0].Equal(cert) {
+		chain = append([]*x509.Certificate{cert}, chain...)
+	}
+
+	rootBytes, err := ioutil.ReadFile(rootCertFile)
+	if err != nil {
+		return nil, fmt.Errorf("root certificate file reading failure (%v)", err)
+	}
+	root, err := pki.ParsePemEncodedCertificate(rootBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyChainLinks(chain, root); err != nil {
+		return nil, err
+	}
+
+	return &SignerBundle{Cert: cert, Priv: key, Chain: chain}, nil
+}
+
+// verifyChainLinks checks that each certificate in chain is signed by the
+// next, and that the last one is signed by root.
+func verifyChainLinks(chain []*x509.Certificate, root *x509.Certificate) error {
+	issuers := append(append([]*x509.Certificate{}, chain[1:]...), root)
+	for i, cert := range chain {
+		if err := cert.CheckSignatureFrom(issuers[i]); err != nil {
+			return fmt.Errorf("certificate chain verification failed at position %d (%v)", i, err)
+		}
+	}
+	return nil
+}
+
+// VerifyChain checks that leafPEM chains up to one of the certificates in
+// rootsPEM, using the standard library's x509 path-building verifier.
+// intermediatesPEM holds any certificates (e.g. a Citadel intermediate CA)
+// the leaf's issuer needs but a peer wouldn't already trust as a root; pass
+// nil if leafPEM is signed directly by a root. It is intended as a
+// startup-time sanity check that a CA's own issuance is internally
+// consistent, not as a substitute for proper peer validation.
+func VerifyChain(leafPEM, intermediatesPEM, rootsPEM []byte) error {
+	leaf, err := pki.ParsePemEncodedCertificate(leafPEM)
+	if err != nil {
+		return err
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(rootsPEM) {
+		return fmt.Errorf("failed to parse root certificates")
+	}
+
+	intermediates := x509.NewCertPool()
+	if len(intermediatesPEM) > 0 && !intermediates.AppendCertsFromPEM(intermediatesPEM) {
+		return fmt.Errorf("failed to parse intermediate certificates")
+	}
+
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("certificate chain verification failed (%v)", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"testing"
+	"time"
+
+	"istio.io/istio/security/pkg/pki"
+)
+
+// genTestChain builds a root CA, an intermediate CA signed by the root, and
+// a leaf signed by the intermediate - the multi-tier hierarchy VerifyChain
+// needs to handle.
+func genTestChain(t *testing.T) (rootPEM, rootKeyPEM, interPEM, interKeyPEM, leafPEM []byte) {
+	t.Helper()
+
+	rootPEM, rootKeyPEM, err := GenCert(CertOptions{
+		Org: "root", IsCA: true, IsSelfSigned: true, TTL: time.Hour, RSAKeySize: 2048,
+	})
+	if err != nil {
+		t.Fatalf("failed to generate root cert: %v", err)
+	}
+	rootCert, err := pki.ParsePemEncodedCertificate(rootPEM)
+	if err != nil {
+		t.Fatalf("failed to parse root cert: %v", err)
+	}
+	rootKey, err := pki.ParsePemEncodedKey(rootKeyPEM)
+	if err != nil {
+		t.Fatalf("failed to parse root key: %v", err)
+	}
+
+	interPEM, interKeyPEM, err = GenCert(CertOptions{
+		Org: "intermediate", IsCA: true, TTL: time.Hour, RSAKeySize: 2048,
+		SignerCert: rootCert, SignerPriv: rootKey,
+	})
+	if err != nil {
+		t.Fatalf("failed to generate intermediate cert: %v", err)
+	}
+	interCert, err := pki.ParsePemEncodedCertificate(interPEM)
+	if err != nil {
+		t.Fatalf("failed to parse intermediate cert: %v", err)
+	}
+	interKey, err := pki.ParsePemEncodedKey(interKeyPEM)
+	if err != nil {
+		t.Fatalf("failed to parse intermediate key: %v", err)
+	}
+
+	leafPEM, _, err = GenCert(CertOptions{
+		Host: "workload.istio.io", Org: "leaf", TTL: time.Hour, RSAKeySize: 2048,
+		SignerCert: interCert, SignerPriv: interKey,
+	})
+	if err != nil {
+		t.Fatalf("failed to generate leaf cert: %v", err)
+	}
+
+	return rootPEM, rootKeyPEM, interPEM, interKeyPEM, leafPEM
+}
+
+func TestVerifyChain(t *testing.T) {
+	rootPEM, _, interPEM, _, leafPEM := genTestChain(t)
+
+	if err := VerifyChain(leafPEM, interPEM, rootPEM); err != nil {
+		t.Errorf("VerifyChain should succeed for a leaf chaining through its intermediate: %v", err)
+	}
+
+	if err := VerifyChain(leafPEM, nil, rootPEM); err == nil {
+		t.Error("VerifyChain should fail when the intermediate pool is omitted")
+	}
+
+	otherRootPEM, _, _, _, _ := genTestChain(t)
+	if err := VerifyChain(leafPEM, interPEM, otherRootPEM); err == nil {
+		t.Error("VerifyChain should fail against an unrelated root")
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"time"
+
+	"istio.io/istio/security/pkg/pki/revocation"
+)
+
+// GenerateCRL builds a DER-encoded X.509 CRL, signed by signerKey on behalf
+// of signerCert, listing every certificate that store reports as revoked.
+// The CRL is valid from now until ttl from now, matching how CertOptions.TTL
+// governs leaf/CA cert lifetimes.
+func GenerateCRL(signerCert *x509.Certificate, signerKey crypto.PrivateKey, store revocation.RevocationStore, ttl time.Duration) ([]byte, error) {
+	revoked, err := store.RevokedCerts()
+	if err != nil {
+		return nil, fmt.Errorf("CRL generation fails at reading the revocation store (%v)", err)
+	}
+
+	now := time.Now()
+	crlBytes, err := signerCert.CreateCRL(rand.Reader, signerKey, revoked, now, now.Add(ttl))
+	if err != nil {
+		return nil, fmt.Errorf("CRL generation fails at X509 CRL creation (%v)", err)
+	}
+	return crlBytes, nil
+}
+
+// CRLHandler returns an http.Handler suitable for mounting at /crl on the CA
+// server. It serves the current DER-encoded CRL with caching headers set to
+// ttl so that Envoy filter chains (or any client polling the CRL endpoint
+// for a CertificateValidationContext) don't hammer the CA on every request.
+func CRLHandler(signerCert *x509.Certificate, signerKey crypto.PrivateKey, store revocation.RevocationStore, ttl time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		crlBytes, err := GenerateCRL(signerCert, signerKey, store, ttl)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pkix-crl")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(ttl.Seconds())))
+		w.WriteHeader(http.StatusOK)
+		w.Write(crlBytes) // nolint: errcheck
+	})
+}
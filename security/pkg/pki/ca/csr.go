@@ -0,0 +1,125 @@
+// Copyright 2017 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+
+	"istio.io/istio/security/pkg/pki"
+)
+
+// GenCSR generates a PKCS#10 certificate signing request and the private key
+// it is bound to, mirroring GenCert but emitting a CERTIFICATE REQUEST block
+// instead of a signed certificate. This lets a workload (Envoy/node-agent)
+// keep its private key local and send only the CSR to the CA.
+func GenCSR(options CertOptions) (csrPEM []byte, keyPEM []byte, err error) {
+	priv, err := genKeyPair(options.KeyType, options.ECCurve, options.RSAKeySize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("CSR generation fails at private key generation (%v)", err)
+	}
+
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			Organization: []string{options.Org},
+		},
+	}
+
+	if h := options.Host; len(h) > 0 {
+		s, err := buildSubjectAltNameExtension(h)
+		if err != nil {
+			return nil, nil, err
+		}
+		template.ExtraExtensions = []pkix.Extension{*s}
+	}
+
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, template, priv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("CSR generation fails at X509 CSR creation (%v)", err)
+	}
+
+	csrPEM, keyPEM, err = encodePem(true, csrBytes, priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return csrPEM, keyPEM, nil
+}
+
+// CSRPolicyFunc validates a parsed CSR (in particular its requested SANs)
+// before it is signed. It should return a non-nil error to reject the CSR.
+type CSRPolicyFunc func(*x509.CertificateRequest) error
+
+// SignCSR parses a PKCS#10 CSR, validates its requested SANs against policy,
+// and issues a certificate bound to the CSR's public key using the signer
+// credentials in options (options.SignerCert / options.SignerPriv). Unlike
+// GenCert, the private key never exists in this process - this is the
+// standard SPIFFE workload-API pattern where the workload generates and
+// keeps its own key.
+func SignCSR(csrPEM []byte, options CertOptions, policy CSRPolicyFunc) (certPEM []byte, err error) {
+	csr, err := pki.ParsePemEncodedCSR(csrPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("CSR signature verification failed (%v)", err)
+	}
+
+	if policy != nil {
+		if err := policy(csr); err != nil {
+			return nil, fmt.Errorf("CSR rejected by policy (%v)", err)
+		}
+	}
+
+	template, err := genCertTemplateFromCSR(csr, options)
+	if err != nil {
+		return nil, fmt.Errorf("cert generation fails at cert template creation (%v)", err)
+	}
+
+	signerCert, signerKey := options.SignerCert, options.SignerPriv
+	if options.Signer != nil {
+		signerCert, signerKey = options.Signer.Certificate(), options.Signer
+	}
+
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, signerCert, csr.PublicKey, signerKey)
+	if err != nil {
+		return nil, fmt.Errorf("cert generation fails at X509 cert creation (%v)", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes}), nil
+}
+
+// genCertTemplateFromCSR builds a certificate template identical to
+// genCertTemplate, except that the Subject Alternative Name extension is
+// copied verbatim from the CSR rather than derived from options.Host - the
+// requested SANs are the CSR's own, and have already been checked against
+// the caller's CSRPolicyFunc by the time this is called.
+func genCertTemplateFromCSR(csr *x509.CertificateRequest, options CertOptions) (*x509.Certificate, error) {
+	opts := options
+	opts.Host = ""
+	template, err := genCertTemplate(opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, ext := range csr.Extensions {
+		if ext.Id.Equal(pki.OIDSubjectAlternativeName) {
+			template.ExtraExtensions = append(template.ExtraExtensions, ext)
+		}
+	}
+	return template, nil
+}
@@ -20,6 +20,9 @@ package ca
 
 import (
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -27,12 +30,14 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"math/big"
 	"net"
 	"strings"
 	"time"
 
 	"istio.io/istio/security/pkg/pki"
+	"istio.io/istio/security/pkg/pki/revocation"
 )
 
 // CertOptions contains options for generating a new certificate.
@@ -54,6 +59,17 @@ type CertOptions struct {
 	// Signer private key (PEM encoded).
 	SignerPriv crypto.PrivateKey
 
+	// Signer, when set, is used instead of SignerCert/SignerPriv. This is
+	// the extension point for keys that never leave an HSM/KMS boundary -
+	// see the Signer interface for details.
+	Signer Signer
+
+	// SignerCertChain is the ordered chain of certificates, starting with
+	// SignerCert itself, that a peer needs (but may not already trust) to
+	// validate the issued leaf up to a root. When set, GenCert appends it
+	// to the returned pemCert, producing a fullchain PEM.
+	SignerCertChain []*x509.Certificate
+
 	// Organization for this certificate.
 	Org string
 
@@ -71,38 +87,151 @@ type CertOptions struct {
 
 	// The size of RSA private key to be generated.
 	RSAKeySize int
+
+	// The type of private key to be generated. Defaults to RSA if unset.
+	KeyType KeyType
+
+	// The elliptic curve to use when KeyType is one of the ECDSA key types.
+	ECCurve ECCurve
+
+	// KeyUsage overrides the default key usage bits derived from IsCA. Zero
+	// value means "use the default". For a CA cert, KeyUsageCertSign and
+	// KeyUsageCRLSign are always added, whatever KeyUsage is set to.
+	KeyUsage x509.KeyUsage
+
+	// ExtKeyUsage overrides the default extended key usages derived from
+	// IsServer/IsClient. Nil means "use the default".
+	ExtKeyUsage []x509.ExtKeyUsage
+
+	// MaxPathLen is the maximum number of non-self-issued intermediate
+	// certificates that may follow this one in a valid chain. Only
+	// meaningful when IsCA is true. See x509.Certificate.MaxPathLen.
+	MaxPathLen int
+
+	// MaxPathLenZero indicates that MaxPathLen should be treated as
+	// explicitly zero, rather than "unset". See x509.Certificate.MaxPathLenZero.
+	MaxPathLenZero bool
+
+	// CRLDistributionPoints are the URLs at which this cert's issuer
+	// publishes the CRL that would list this certificate if revoked.
+	CRLDistributionPoints []string
+
+	// RevocationStore, when set, has every issued serial number recorded
+	// against it at issuance time so it can later be revoked and published
+	// in a CRL. A failed Record is logged and does not stop issuance.
+	RevocationStore revocation.RevocationStore
 }
 
+// KeyType identifies the algorithm family of the private key generated for a
+// certificate. Workloads with constrained CPU budgets (e.g. a sidecar per
+// pod) pay a real cost for RSA signing on every handshake, so ECDSA and
+// Ed25519 are offered as cheaper alternatives.
+type KeyType int
+
+const (
+	// RSA is the default key type, matching historical behavior.
+	RSA KeyType = iota
+	// ECDSA generates an elliptic-curve key on the curve selected by ECCurve.
+	ECDSA
+	// Ed25519 generates an Ed25519 key. ECCurve is ignored for this type.
+	Ed25519
+)
+
+// ECCurve identifies the elliptic curve to use for ECDSA keys.
+type ECCurve int
+
+const (
+	// ECCurveP256 selects the NIST P-256 curve.
+	ECCurveP256 ECCurve = iota
+	// ECCurveP384 selects the NIST P-384 curve.
+	ECCurveP384
+	// ECCurveP521 selects the NIST P-521 curve.
+	ECCurveP521
+)
+
 // URIScheme is the URI scheme for Istio identities.
 const URIScheme = "spiffe"
 
 // GenCert generates a X.509 certificate and a private key with the given options.
 func GenCert(options CertOptions) (pemCert []byte, pemKey []byte, err error) {
-	// Generates a RSA private&public key pair.
-	// The public key will be bound to the certificate generated below. The
-	// private key will be used to sign this certificate in the self-signed
-	// case, otherwise the certificate is signed by the signer private key
-	// as specified in the CertOptions.
-	priv, err := rsa.GenerateKey(rand.Reader, options.RSAKeySize)
+	// Generates a private&public key pair. The public key will be bound to
+	// the certificate generated below. When options.Signer is set, it signs
+	// this certificate, even if IsSelfSigned is also set. Otherwise the
+	// private key is used in the self-signed case, and the signer private key
+	// as specified in the CertOptions in every other case.
+	priv, err := genKeyPair(options.KeyType, options.ECCurve, options.RSAKeySize)
 	if err != nil {
-		return nil, nil, fmt.Errorf("cert generation fails at RSA key generation (%v)", err)
+		return nil, nil, fmt.Errorf("cert generation fails at private key generation (%v)", err)
 	}
 	template, err := genCertTemplate(options)
 	if err != nil {
 		return nil, nil, fmt.Errorf("cert generation fails at cert template creation (%v)", err)
 	}
-	signerCert, signerKey := template, crypto.PrivateKey(priv)
-	if !options.IsSelfSigned {
+	signerCert, signerKey := template, priv
+	switch {
+	case options.Signer != nil:
+		signerCert, signerKey = options.Signer.Certificate(), options.Signer
+	case !options.IsSelfSigned:
 		signerCert, signerKey = options.SignerCert, options.SignerPriv
 	}
-	certBytes, err := x509.CreateCertificate(rand.Reader, template, signerCert, &priv.PublicKey, signerKey)
+	pub := publicKey(priv)
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, signerCert, pub, signerKey)
 	if err != nil {
 		return nil, nil, fmt.Errorf("cert generation fails at X509 cert creation (%v)", err)
 	}
 
-	pemCert, pemKey = encodePem(false, certBytes, priv)
-	err = nil
-	return
+	pemCert, pemKey, err = encodePem(false, certBytes, priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range options.SignerCertChain {
+		pemCert = append(pemCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	return pemCert, pemKey, nil
+}
+
+// genKeyPair generates a private key of the requested type.
+func genKeyPair(keyType KeyType, curve ECCurve, rsaKeySize int) (crypto.PrivateKey, error) {
+	switch keyType {
+	case ECDSA:
+		c, err := ellipticCurve(curve)
+		if err != nil {
+			return nil, err
+		}
+		return ecdsa.GenerateKey(c, rand.Reader)
+	case Ed25519:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	default:
+		return rsa.GenerateKey(rand.Reader, rsaKeySize)
+	}
+}
+
+func ellipticCurve(curve ECCurve) (elliptic.Curve, error) {
+	switch curve {
+	case ECCurveP256:
+		return elliptic.P256(), nil
+	case ECCurveP384:
+		return elliptic.P384(), nil
+	case ECCurveP521:
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("unsupported EC curve %v", curve)
+	}
+}
+
+// publicKey extracts the public key out of a supported private key type.
+func publicKey(priv crypto.PrivateKey) crypto.PublicKey {
+	switch k := priv.(type) {
+	case *rsa.PrivateKey:
+		return &k.PublicKey
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey
+	case ed25519.PrivateKey:
+		return k.Public()
+	default:
+		return nil
+	}
 }
 
 // LoadSignerCredsFromFiles loads the signer cert&key from the given files.
@@ -131,16 +260,22 @@ func LoadSignerCredsFromFiles(signerCertFile string, signerPrivFile string) (*x5
 	return cert, key, nil
 }
 
-func encodePem(isCSR bool, csrOrCert []byte, priv *rsa.PrivateKey) ([]byte, []byte) {
+// encodePem PEM-encodes a certificate (or CSR, when isCSR is set) together
+// with its private key. Keys are encoded as PKCS#8 ("BEGIN PRIVATE KEY") so
+// that any of RSA, ECDSA, or Ed25519 round-trip through the same block type.
+func encodePem(isCSR bool, csrOrCert []byte, priv crypto.PrivateKey) ([]byte, []byte, error) {
 	encodeMsg := "CERTIFICATE"
 	if isCSR {
 		encodeMsg = "CERTIFICATE REQUEST"
 	}
 	csrOrCertPem := pem.EncodeToMemory(&pem.Block{Type: encodeMsg, Bytes: csrOrCert})
 
-	privDer := x509.MarshalPKCS1PrivateKey(priv)
-	privPem := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: privDer})
-	return csrOrCertPem, privPem
+	privDer, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("PEM encoding fails at private key marshaling (%v)", err)
+	}
+	privPem := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDer})
+	return csrOrCertPem, privPem, nil
 }
 
 func genSerialNum() (*big.Int, error) {
@@ -154,21 +289,26 @@ func genSerialNum() (*big.Int, error) {
 
 // genCertTemplate generates a certificate template with the given options.
 func genCertTemplate(options CertOptions) (*x509.Certificate, error) {
-	var keyUsage x509.KeyUsage
-	if options.IsCA {
-		// If the cert is a CA cert, the private key is allowed to sign other certificate.
-		keyUsage = x509.KeyUsageCertSign
-	} else {
-		// Otherwise the private key is allowed for digital signature and key encipherment.
-		keyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+	keyUsage := options.KeyUsage
+	if keyUsage == 0 {
+		if options.IsCA {
+			// If the cert is a CA cert, the private key is allowed to sign other certificate.
+			keyUsage = x509.KeyUsageCertSign
+		} else {
+			// Otherwise the private key is allowed for digital signature and key encipherment.
+			keyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment
+		}
 	}
 
-	extKeyUsages := []x509.ExtKeyUsage{}
-	if options.IsServer {
-		extKeyUsages = append(extKeyUsages, x509.ExtKeyUsageServerAuth)
-	}
-	if options.IsClient {
-		extKeyUsages = append(extKeyUsages, x509.ExtKeyUsageClientAuth)
+	extKeyUsages := options.ExtKeyUsage
+	if extKeyUsages == nil {
+		extKeyUsages = []x509.ExtKeyUsage{}
+		if options.IsServer {
+			extKeyUsages = append(extKeyUsages, x509.ExtKeyUsageServerAuth)
+		}
+		if options.IsClient {
+			extKeyUsages = append(extKeyUsages, x509.ExtKeyUsageClientAuth)
+		}
 	}
 
 	notBefore := options.NotBefore
@@ -181,6 +321,15 @@ func genCertTemplate(options CertOptions) (*x509.Certificate, error) {
 	if err != nil {
 		return nil, err
 	}
+	if options.RevocationStore != nil {
+		// A store write failure shouldn't take down cert issuance: the CRL
+		// is a best-effort revocation signal, and a CA that stops minting
+		// certs because its revocation log had a transient write error is a
+		// worse outage than a cert the store briefly doesn't know about.
+		if err := options.RevocationStore.Record(serialNum); err != nil {
+			log.Printf("failed to record serial number %s in revocation store: %v", serialNum, err)
+		}
+	}
 
 	template := &x509.Certificate{
 		SerialNumber: serialNum,
@@ -192,6 +341,7 @@ func genCertTemplate(options CertOptions) (*x509.Certificate, error) {
 		KeyUsage:              keyUsage,
 		ExtKeyUsage:           extKeyUsages,
 		BasicConstraintsValid: true,
+		CRLDistributionPoints: options.CRLDistributionPoints,
 	}
 
 	if h := options.Host; len(h) > 0 {
@@ -204,7 +354,9 @@ func genCertTemplate(options CertOptions) (*x509.Certificate, error) {
 
 	if options.IsCA {
 		template.IsCA = true
-		template.KeyUsage |= x509.KeyUsageCertSign
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+		template.MaxPathLen = options.MaxPathLen
+		template.MaxPathLenZero = options.MaxPathLenZero
 	}
 	return template, nil
 }
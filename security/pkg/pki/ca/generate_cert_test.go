@@ -0,0 +1,133 @@
+// Copyright 2017 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"crypto/x509"
+	"fmt"
+	"testing"
+	"time"
+
+	"istio.io/istio/security/pkg/pki"
+)
+
+func TestGenCertKeyTypes(t *testing.T) {
+	cases := []struct {
+		name    string
+		keyType KeyType
+		ecCurve ECCurve
+	}{
+		{"RSA", RSA, 0},
+		{"ECDSA-P256", ECDSA, ECCurveP256},
+		{"ECDSA-P384", ECDSA, ECCurveP384},
+		{"Ed25519", Ed25519, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			options := CertOptions{
+				Host:         "test.istio.io",
+				Org:          "Istio Test",
+				IsCA:         true,
+				IsSelfSigned: true,
+				TTL:          time.Hour,
+				RSAKeySize:   2048,
+				KeyType:      c.keyType,
+				ECCurve:      c.ecCurve,
+			}
+
+			pemCert, pemKey, err := GenCert(options)
+			if err != nil {
+				t.Fatalf("GenCert failed: %v", err)
+			}
+
+			cert, err := pki.ParsePemEncodedCertificate(pemCert)
+			if err != nil {
+				t.Fatalf("failed to parse generated cert: %v", err)
+			}
+			if err := cert.CheckSignatureFrom(cert); err != nil {
+				t.Errorf("self-signed cert does not verify against its own key: %v", err)
+			}
+
+			key, err := pki.ParsePemEncodedKey(pemKey)
+			if err != nil {
+				t.Fatalf("failed to round-trip PKCS#8 key: %v", err)
+			}
+			if key == nil {
+				t.Fatal("parsed key is nil")
+			}
+		})
+	}
+}
+
+func TestSignCSR(t *testing.T) {
+	signerPEMCert, signerPEMKey, err := GenCert(CertOptions{
+		Org:          "Istio Test CA",
+		IsCA:         true,
+		IsSelfSigned: true,
+		TTL:          time.Hour,
+		RSAKeySize:   2048,
+	})
+	if err != nil {
+		t.Fatalf("failed to generate signer cert: %v", err)
+	}
+	signerCert, err := pki.ParsePemEncodedCertificate(signerPEMCert)
+	if err != nil {
+		t.Fatalf("failed to parse signer cert: %v", err)
+	}
+	signerKey, err := pki.ParsePemEncodedKey(signerPEMKey)
+	if err != nil {
+		t.Fatalf("failed to parse signer key: %v", err)
+	}
+
+	csrPEM, _, err := GenCSR(CertOptions{
+		Host:       "workload.istio.io",
+		Org:        "Istio Test",
+		RSAKeySize: 2048,
+	})
+	if err != nil {
+		t.Fatalf("GenCSR failed: %v", err)
+	}
+
+	t.Run("accepted by policy", func(t *testing.T) {
+		certPEM, err := SignCSR(csrPEM, CertOptions{
+			TTL:        time.Hour,
+			SignerCert: signerCert,
+			SignerPriv: signerKey,
+		}, func(*x509.CertificateRequest) error { return nil })
+		if err != nil {
+			t.Fatalf("SignCSR failed: %v", err)
+		}
+		leaf, err := pki.ParsePemEncodedCertificate(certPEM)
+		if err != nil {
+			t.Fatalf("failed to parse issued cert: %v", err)
+		}
+		if err := leaf.CheckSignatureFrom(signerCert); err != nil {
+			t.Errorf("issued cert does not chain to signer: %v", err)
+		}
+	})
+
+	t.Run("rejected by policy", func(t *testing.T) {
+		wantErr := "nope"
+		_, err := SignCSR(csrPEM, CertOptions{
+			TTL:        time.Hour,
+			SignerCert: signerCert,
+			SignerPriv: signerKey,
+		}, func(*x509.CertificateRequest) error { return fmt.Errorf(wantErr) })
+		if err == nil {
+			t.Fatal("expected SignCSR to reject the CSR, got nil error")
+		}
+	})
+}
@@ -0,0 +1,122 @@
+// Copyright 2017 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"io"
+)
+
+// Signer abstracts the CA's signing key away from GenCert/SignCSR. Its
+// method set is exactly crypto.Signer plus Certificate(), so a Signer can be
+// passed anywhere a crypto.Signer is expected (x509.CreateCertificate takes
+// an interface{} and type-asserts it). The private key backing a Signer
+// implementation never has to be held in process memory - FileSigner is the
+// exception kept for backward compatibility; PKCS11Signer only ever performs
+// HSM-bound Sign operations, and the cloud KMS signers hold no key material
+// (their Sign methods are stubs that return an error).
+type Signer interface {
+	Public() crypto.PublicKey
+	Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error)
+	Certificate() *x509.Certificate
+}
+
+// FileSigner is a Signer backed by a PEM-encoded cert and private key on
+// local disk - the CA's original, pre-Signer-interface behavior.
+type FileSigner struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+// NewFileSigner loads a FileSigner from the given cert/key file pair.
+func NewFileSigner(certFile, keyFile string) (*FileSigner, error) {
+	cert, key, err := LoadSignerCredsFromFiles(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("key loaded from %q does not implement crypto.Signer", keyFile)
+	}
+	return &FileSigner{cert: cert, key: signer}, nil
+}
+
+// Public implements Signer.
+func (s *FileSigner) Public() crypto.PublicKey { return s.key.Public() }
+
+// Sign implements Signer.
+func (s *FileSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.key.Sign(rand, digest, opts)
+}
+
+// Certificate implements Signer.
+func (s *FileSigner) Certificate() *x509.Certificate { return s.cert }
+
+// GCPKMSSigner is a Signer backed by a key held in Google Cloud KMS. The
+// private key material never leaves KMS. This is a stub: Sign makes no
+// call to the KMS AsymmetricSign API and always returns an error.
+type GCPKMSSigner struct {
+	// KeyResourceName is the full KMS CryptoKeyVersion resource name, e.g.
+	// "projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1".
+	KeyResourceName string
+	cert            *x509.Certificate
+	pub             crypto.PublicKey
+}
+
+// NewGCPKMSSigner returns a GCPKMSSigner for the given KMS key and CA cert.
+// pub must be fetched once (via KMS GetPublicKey) and cached by the caller.
+func NewGCPKMSSigner(keyResourceName string, cert *x509.Certificate, pub crypto.PublicKey) *GCPKMSSigner {
+	return &GCPKMSSigner{KeyResourceName: keyResourceName, cert: cert, pub: pub}
+}
+
+// Public implements Signer.
+func (s *GCPKMSSigner) Public() crypto.PublicKey { return s.pub }
+
+// Sign implements Signer.
+func (s *GCPKMSSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return nil, fmt.Errorf("GCPKMSSigner.Sign: not implemented, wire in cloud.google.com/go/kms AsymmetricSign for %s", s.KeyResourceName)
+}
+
+// Certificate implements Signer.
+func (s *GCPKMSSigner) Certificate() *x509.Certificate { return s.cert }
+
+// AWSKMSSigner is a Signer backed by an asymmetric key held in AWS KMS. The
+// private key material never leaves KMS. This is a stub: Sign makes no
+// call to the KMS Sign API and always returns an error.
+type AWSKMSSigner struct {
+	// KeyID is the KMS key ID or ARN.
+	KeyID string
+	cert  *x509.Certificate
+	pub   crypto.PublicKey
+}
+
+// NewAWSKMSSigner returns an AWSKMSSigner for the given KMS key and CA cert.
+// pub must be fetched once (via KMS GetPublicKey) and cached by the caller.
+func NewAWSKMSSigner(keyID string, cert *x509.Certificate, pub crypto.PublicKey) *AWSKMSSigner {
+	return &AWSKMSSigner{KeyID: keyID, cert: cert, pub: pub}
+}
+
+// Public implements Signer.
+func (s *AWSKMSSigner) Public() crypto.PublicKey { return s.pub }
+
+// Sign implements Signer.
+func (s *AWSKMSSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return nil, fmt.Errorf("AWSKMSSigner.Sign: not implemented, wire in aws-sdk-go kms.Sign for %s", s.KeyID)
+}
+
+// Certificate implements Signer.
+func (s *AWSKMSSigner) Certificate() *x509.Certificate { return s.cert }
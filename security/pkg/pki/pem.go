@@ -0,0 +1,111 @@
+// Copyright 2017 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pki
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+)
+
+// ParsePemEncodedCertificate constructs a `x509.Certificate` object using the
+// given PEM-encoded certificate.
+func ParsePemEncodedCertificate(certBytes []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certBytes)
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM encoded certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse X.509 certificate (%v)", err)
+	}
+
+	return cert, nil
+}
+
+// ParsePemEncodedCertificateChain parses a sequence of concatenated
+// PEM-encoded certificates (as found in a typical "fullchain.pem" file) and
+// returns them in the order they appear.
+func ParsePemEncodedCertificateChain(chainBytes []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	rest := chainBytes
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse X.509 certificate in chain (%v)", err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no certificates found in chain")
+	}
+	return chain, nil
+}
+
+// ParsePemEncodedCSR constructs a `x509.CertificateRequest` object using the
+// given PEM-encoded PKCS#10 certificate signing request.
+func ParsePemEncodedCSR(csrBytes []byte) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode(csrBytes)
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM encoded CSR")
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse X.509 certificate request (%v)", err)
+	}
+
+	return csr, nil
+}
+
+// ParsePemEncodedKey parses a PEM-encoded private key and returns a
+// `crypto.PrivateKey`. It supports PKCS#1 and PKCS#8 encoded RSA keys, SEC 1
+// encoded ECDSA keys, and PKCS#8 encoded Ed25519 keys. GenCert itself always
+// emits PKCS#8, whichever KeyType is requested.
+func ParsePemEncodedKey(keyBytes []byte) (crypto.PrivateKey, error) {
+	block, _ := pem.Decode(keyBytes)
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM encoded key")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse PKCS1-encoded RSA key (%v)", err)
+		}
+		return key, nil
+	case "EC PRIVATE KEY":
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse EC key (%v)", err)
+		}
+		return key, nil
+	default:
+		// PKCS8 covers RSA, ECDSA, and Ed25519 keys alike.
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse PKCS8-encoded key (%v)", err)
+		}
+		return key, nil
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2017 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package revocation tracks certificates issued by the CA so that they can
+// be revoked and published in a CRL.
+package revocation
+
+import (
+	"bufio"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// oidCRLReasonCode is the RFC 5280 CRL entry extension OID that carries the
+// revocation reason code (4.2.1.5.1 / 5.3.1).
+var oidCRLReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+// toRevokedCertificate converts an Entry into the pkix type x509.CreateCRL
+// consumes, embedding Reason as the standard CRL reason-code extension when
+// it is set (the zero value, Unspecified, is the one code that is safe to
+// omit per RFC 5280 5.3.1).
+func toRevokedCertificate(e *Entry) (pkix.RevokedCertificate, error) {
+	rc := pkix.RevokedCertificate{
+		SerialNumber:   e.Serial,
+		RevocationTime: e.RevokedAt,
+	}
+	if e.Reason != 0 {
+		reasonBytes, err := asn1.Marshal(asn1.Enumerated(e.Reason))
+		if err != nil {
+			return pkix.RevokedCertificate{}, fmt.Errorf("failed to encode CRL reason code (%v)", err)
+		}
+		rc.Extensions = []pkix.Extension{{Id: oidCRLReasonCode, Value: reasonBytes}}
+	}
+	return rc, nil
+}
+
+// Entry records the revocation state of a single issued certificate.
+type Entry struct {
+	Serial    *big.Int
+	IssuedAt  time.Time
+	RevokedAt time.Time
+	Reason    int
+	IsRevoked bool
+}
+
+// RevocationStore records the serials of certs issued by the CA and tracks
+// which of them have been revoked.
+type RevocationStore interface {
+	// Record registers a newly-issued serial, prior to revocation.
+	Record(serial *big.Int) error
+	// Revoke marks serial as revoked for the given RFC 5280 CRL reason code.
+	Revoke(serial *big.Int, reason int) error
+	// RevokedCerts returns the current revocation list in the form
+	// x509.CreateCRL expects.
+	RevokedCerts() ([]pkix.RevokedCertificate, error)
+}
+
+// MemoryStore is an in-memory RevocationStore. It does not persist across
+// process restarts and is intended for tests and single-CA-process setups
+// where the CRL only needs to reflect revocations issued since startup.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*Entry
+}
+
+// NewMemoryStore creates an empty in-memory RevocationStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*Entry)}
+}
+
+// Record implements RevocationStore.
+func (s *MemoryStore) Record(serial *big.Int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[serial.String()] = &Entry{Serial: serial, IssuedAt: time.Now()}
+	return nil
+}
+
+// Revoke implements RevocationStore.
+func (s *MemoryStore) Revoke(serial *big.Int, reason int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[serial.String()]
+	if !ok {
+		e = &Entry{Serial: serial}
+		s.entries[serial.String()] = e
+	}
+	e.IsRevoked = true
+	e.Reason = reason
+	e.RevokedAt = time.Now()
+	return nil
+}
+
+// RevokedCerts implements RevocationStore.
+func (s *MemoryStore) RevokedCerts() ([]pkix.RevokedCertificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var revoked []pkix.RevokedCertificate
+	for _, e := range s.entries {
+		if e.IsRevoked {
+			rc, err := toRevokedCertificate(e)
+			if err != nil {
+				return nil, err
+			}
+			revoked = append(revoked, rc)
+		}
+	}
+	return revoked, nil
+}
+
+// FileStore is a RevocationStore backed by a newline-delimited JSON file,
+// one Entry appended per Record/Revoke call. Issuance calls Record on every
+// cert, so writes are append-only rather than a full rewrite; when a serial
+// has more than one line (issued, then later revoked), the last line for
+// that serial wins. It is meant for a single CA process that needs
+// revocations to survive a restart; it is not safe for concurrent use by
+// multiple processes.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileStore opens (or creates) path as the backing store.
+func NewFileStore(path string) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open revocation store %q (%v)", path, err)
+	}
+	f.Close() // nolint: errcheck
+	return &FileStore{path: path}, nil
+}
+
+// load reads every entry line in the store. When a serial appears more than
+// once, the last line read wins, so append acts as an incremental update.
+func (s *FileStore) load() (map[string]*Entry, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revocation store %q (%v)", s.path, err)
+	}
+	defer f.Close() // nolint: errcheck
+
+	entries := make(map[string]*Entry)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("failed to parse revocation store entry (%v)", err)
+		}
+		entries[e.Serial.String()] = &e
+	}
+	return entries, scanner.Err()
+}
+
+// append writes a single entry as one more line, without reading or
+// rewriting the rest of the file.
+func (s *FileStore) append(e *Entry) error {
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write revocation store %q (%v)", s.path, err)
+	}
+	defer f.Close() // nolint: errcheck
+
+	if err := json.NewEncoder(f).Encode(e); err != nil {
+		return fmt.Errorf("failed to encode revocation store entry (%v)", err)
+	}
+	return nil
+}
+
+// Record implements RevocationStore.
+func (s *FileStore) Record(serial *big.Int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.append(&Entry{Serial: serial, IssuedAt: time.Now()})
+}
+
+// Revoke implements RevocationStore.
+func (s *FileStore) Revoke(serial *big.Int, reason int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.append(&Entry{Serial: serial, IsRevoked: true, Reason: reason, RevokedAt: time.Now()})
+}
+
+// RevokedCerts implements RevocationStore.
+func (s *FileStore) RevokedCerts() ([]pkix.RevokedCertificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var revoked []pkix.RevokedCertificate
+	for _, e := range entries {
+		if e.IsRevoked {
+			rc, err := toRevokedCertificate(e)
+			if err != nil {
+				return nil, err
+			}
+			revoked = append(revoked, rc)
+		}
+	}
+	return revoked, nil
+}
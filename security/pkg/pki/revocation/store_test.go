@@ -0,0 +1,84 @@
+// Copyright 2017 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revocation
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+)
+
+func TestMemoryStoreRevokeRecordsReason(t *testing.T) {
+	s := NewMemoryStore()
+	serial := big.NewInt(42)
+
+	if err := s.Record(serial); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	const keyCompromise = 1
+	if err := s.Revoke(serial, keyCompromise); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+
+	revoked, err := s.RevokedCerts()
+	if err != nil {
+		t.Fatalf("RevokedCerts failed: %v", err)
+	}
+	if len(revoked) != 1 {
+		t.Fatalf("expected 1 revoked cert, got %d", len(revoked))
+	}
+	if revoked[0].SerialNumber.Cmp(serial) != 0 {
+		t.Errorf("unexpected serial: got %v, want %v", revoked[0].SerialNumber, serial)
+	}
+	if len(revoked[0].Extensions) != 1 {
+		t.Fatalf("expected the reason code extension to be set, got %d extensions", len(revoked[0].Extensions))
+	}
+	if !revoked[0].Extensions[0].Id.Equal(oidCRLReasonCode) {
+		t.Errorf("unexpected extension OID: %v", revoked[0].Extensions[0].Id)
+	}
+}
+
+func TestFileStoreRecordThenRevoke(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "revocations.jsonl")
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+
+	serial := big.NewInt(7)
+	if err := s.Record(serial); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	revoked, err := s.RevokedCerts()
+	if err != nil {
+		t.Fatalf("RevokedCerts failed: %v", err)
+	}
+	if len(revoked) != 0 {
+		t.Fatalf("expected no revoked certs before Revoke, got %d", len(revoked))
+	}
+
+	if err := s.Revoke(serial, 0); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+
+	revoked, err = s.RevokedCerts()
+	if err != nil {
+		t.Fatalf("RevokedCerts failed: %v", err)
+	}
+	if len(revoked) != 1 || revoked[0].SerialNumber.Cmp(serial) != 0 {
+		t.Fatalf("expected serial %v to be revoked, got %v", serial, revoked)
+	}
+}
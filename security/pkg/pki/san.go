@@ -0,0 +1,81 @@
+// Copyright 2017 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pki
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+)
+
+// IdentityType represents the type of an identity encoded in a Subject
+// Alternative Name extension.
+type IdentityType int
+
+const (
+	// TypeDNS is the DNSName SAN type.
+	TypeDNS IdentityType = iota
+	// TypeIP is the IPAddress SAN type.
+	TypeIP
+	// TypeURI is the URI SAN type, used to encode SPIFFE identities.
+	TypeURI
+)
+
+// Identity represents a SAN entry of a given type.
+type Identity struct {
+	Type  IdentityType
+	Value []byte
+}
+
+// OIDSubjectAlternativeName is the OID for the X.509 SAN extension.
+var OIDSubjectAlternativeName = asn1.ObjectIdentifier{2, 5, 29, 17}
+
+// general name tags, as defined in RFC 5280 4.2.1.6.
+const (
+	nameTypeDNS = 2
+	nameTypeURI = 6
+	nameTypeIP  = 7
+)
+
+// BuildSANExtension builds a pkix.Extension for the Subject Alternative Name
+// X.509 extension from the given set of identities.
+func BuildSANExtension(ids []Identity) (*pkix.Extension, error) {
+	var rawValues []asn1.RawValue
+	for _, id := range ids {
+		var tag int
+		switch id.Type {
+		case TypeDNS:
+			tag = nameTypeDNS
+		case TypeIP:
+			tag = nameTypeIP
+		case TypeURI:
+			tag = nameTypeURI
+		default:
+			return nil, fmt.Errorf("unsupported SAN identity type %v", id.Type)
+		}
+		rawValues = append(rawValues, asn1.RawValue{Tag: tag, Class: asn1.ClassContextSpecific, Bytes: id.Value})
+	}
+
+	bytes, err := asn1.Marshal(rawValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SAN extension (%v)", err)
+	}
+
+	return &pkix.Extension{
+		Id:       OIDSubjectAlternativeName,
+		Critical: false,
+		Value:    bytes,
+	}, nil
+}